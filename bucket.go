@@ -3,14 +3,22 @@ package nutsdb
 import (
 	"encoding/binary"
 	"errors"
-	"hash/crc32"
+	"time"
 )
 
-var BucketMetaSize int64
-
 const (
-	IdSize = 8
-	DsSize = 2
+	// MaxBucketHeaderSize 是存储桶头（Crc + ChecksumKind + Op + Size + LiveSize + Id + Ds + Timestamp + TTL + NameLen）
+	// 在所有变长字段都取最坏情况编码长度时占用的最大字节数。
+	MaxBucketHeaderSize = 4 + 1 + binary.MaxVarintLen16 + binary.MaxVarintLen32 + binary.MaxVarintLen64 + binary.MaxVarintLen64 + binary.MaxVarintLen16 + binary.MaxVarintLen64 + binary.MaxVarintLen32 + binary.MaxVarintLen32
+	// MinBucketHeaderSize 是存储桶头在所有变长字段都只占一个字节时的最小字节数，
+	// 用于在读取到的字节数不足以构成一个头部时提前返回错误。
+	MinBucketHeaderSize = 4 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1
+
+	// MaxBucketNameLen 是头部中 NameLen 字段被接受的上限。NameLen 来自未经
+	// 信任的磁盘数据，裸转换成 int 时一个被破坏成高位为 1 的 uint64 会在
+	// 64 位平台上变成负数，从而绕过后续 total > len(bytes) 的边界检查；
+	// 在转换前先拒绝明显不合理的大 NameLen，杜绝这个溢出口子。
+	MaxBucketNameLen = 1<<16 - 1
 )
 
 type BucketOperation uint16
@@ -19,21 +27,67 @@ const (
 	BucketInsertOperation BucketOperation = 1
 	BucketUpdateOperation BucketOperation = 2
 	BucketDeleteOperation BucketOperation = 3
+	// BucketExpireOperation 标记此存储桶是被 TTL 清扫器自动过期删除的，
+	// 与用户主动发起的 BucketDeleteOperation 区分开，便于审计/回放时分辨原因。
+	BucketExpireOperation BucketOperation = 4
 )
 
-var ErrBucketCrcInvalid = errors.New("bucket crc invalid")
-
-func init() {
-	BucketMetaSize = GetDiskSizeFromSingleObject(BucketMeta{})
-}
+var (
+	ErrBucketCrcInvalid = errors.New("bucket crc invalid")
+	// ErrHeaderSizeOutOfBounds 表示待解码的数据不足以容纳一个完整的存储桶头，
+	// 或头部中某个变长整数字段读取失败。
+	ErrHeaderSizeOutOfBounds = errors.New("bucket header size out of bounds")
+	// ErrPayloadSizeMismatch 表示头部记录的 Size 与 NameLen 所指示的负载长度不一致。
+	ErrPayloadSizeMismatch = errors.New("bucket payload size mismatch")
+)
 
 // BucketMeta 存储存储桶的元信息。例如，它存储在磁盘中的存储桶的大小。
+// 头部采用与 nutsdb 条目格式一致的编码：固定 4 字节 Crc 与 1 字节
+// ChecksumKind 之后，Op、Size、LiveSize、Id、Ds、Timestamp、TTL、NameLen 均以
+// binary.PutUvarint 写入，因此不同存储桶的头部实际占用的字节数并不相同，
+// 需要通过 HeaderSize 获取。
 type BucketMeta struct {
 	Crc uint32
+	// ChecksumKind: 计算/校验 Crc 所使用的算法，随头部落盘以便旧数据仍可读取。
+	ChecksumKind ChecksumKind
 	// Op: 标记此存储桶的最新操作（例如删除、插入、更新）。
 	Op BucketOperation
-	// Size: 有效负载的大小。
+	// Size: 有效负载（BucketName）的大小，与头部中的 NameLen 交叉校验。
 	Size uint32
+	// LiveSize: 此存储桶中所有未被标记为删除的条目的负载大小之和，
+	// 由 BucketInsertOperation/BucketUpdateOperation/BucketDeleteOperation
+	// 增量维护，供 DB.BucketSize 等监控/配额接口读取。
+	LiveSize uint64
+	// headerSize 是最近一次 Encode/Decode 时头部实际占用的字节数。
+	headerSize int
+}
+
+// ApplyEntryDelta 根据条目操作 op 调整 LiveSize：插入记一笔正的负载大小，
+// 删除记一笔负的负载大小，更新则传入新旧负载大小之差（可正可负）。
+// LiveSize 不会下溢为负数，防止账目错乱的增量把累计值计算穿透到负数区间。
+func (meta *BucketMeta) ApplyEntryDelta(op BucketOperation, delta int64) {
+	switch op {
+	case BucketInsertOperation, BucketUpdateOperation, BucketDeleteOperation:
+	default:
+		return
+	}
+
+	if delta >= 0 {
+		meta.LiveSize += uint64(delta)
+		return
+	}
+
+	shrink := uint64(-delta)
+	if shrink >= meta.LiveSize {
+		meta.LiveSize = 0
+		return
+	}
+	meta.LiveSize -= shrink
+}
+
+// HeaderSize 返回最近一次 Encode 或 Decode 写入/解析的头部实际占用的字节数。
+func (meta *BucketMeta) HeaderSize() int {
+	return meta.headerSize
 }
 
 // Bucket 是存储桶的磁盘结构
@@ -46,60 +100,220 @@ type Bucket struct {
 	Id BucketId
 	// Ds: 此存储桶的数据结构。（列表、 集、 排序集、 字符串）
 	Ds Ds
+	// Timestamp: 此存储桶的创建时间（unix 秒）。
+	Timestamp uint64
+	// TTL: 此存储桶的存活时长（秒），0 表示永不过期。
+	TTL uint32
 	// Name: 此存储桶的名称。
 	Name string
 }
 
-// Decode : CRC | op | size
-func (meta *BucketMeta) Decode(bytes []byte) {
-	_ = bytes[BucketMetaSize-1]
+// Expired 判断此存储桶是否已经过期：TTL 为 0 表示永不过期；否则在
+// Timestamp+TTL 早于 now 时返回 true。
+func (b *Bucket) Expired(now time.Time) bool {
+	if b.TTL == 0 {
+		return false
+	}
+	deadline := time.Unix(int64(b.Timestamp), 0).Add(time.Duration(b.TTL) * time.Second)
+	return now.After(deadline)
+}
+
+// DecodeBucketHeader 只解析头部：固定 4 字节 Crc 与 1 字节 ChecksumKind，
+// 随后依次是变长整数编码的 Op、Size、LiveSize、Id、Ds、Timestamp、TTL、
+// NameLen。调用方据此得知 payload（BucketName）的长度与起始偏移，而无需
+// 预先拥有完整的记录。
+func DecodeBucketHeader(bytes []byte) (meta *BucketMeta, id BucketId, ds Ds, timestamp uint64, ttl uint32, nameLen int, headerSize int, err error) {
+	if len(bytes) < MinBucketHeaderSize {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+
 	crc := binary.LittleEndian.Uint32(bytes[:4])
-	op := binary.LittleEndian.Uint16(bytes[4:6])
-	size := binary.LittleEndian.Uint32(bytes[6:10])
-	meta.Crc = crc
-	meta.Size = size
-	meta.Op = BucketOperation(op)
+	checksumKind := ChecksumKind(bytes[4])
+	offset := 5
+
+	op, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	size, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	liveSize, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	rawId, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	rawDs, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	rawTimestamp, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	rawTTL, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	rawNameLen, n := binary.Uvarint(bytes[offset:])
+	if n <= 0 {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+	offset += n
+
+	if rawNameLen > MaxBucketNameLen {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+
+	if offset > MaxBucketHeaderSize {
+		return nil, 0, 0, 0, 0, 0, 0, ErrHeaderSizeOutOfBounds
+	}
+
+	meta = &BucketMeta{
+		Crc:          crc,
+		ChecksumKind: checksumKind,
+		Op:           BucketOperation(op),
+		Size:         uint32(size),
+		LiveSize:     liveSize,
+		headerSize:   offset,
+	}
+	return meta, BucketId(rawId), Ds(rawDs), rawTimestamp, uint32(rawTTL), int(rawNameLen), offset, nil
+}
+
+// DecodeBucket 从 bytes 中解析出一条完整的存储桶记录：先读取变长整数头，
+// 校验头部记录的 Size 与 NameLen 是否一致，再读取 payload 并校验 Crc。
+// n 是这条记录实际占用的字节数，供调用方（例如流式读取器）推进读取位置。
+func DecodeBucket(bytes []byte) (b *Bucket, n int, err error) {
+	meta, id, ds, timestamp, ttl, nameLen, headerSize, err := DecodeBucketHeader(bytes)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int(meta.Size) != nameLen {
+		return nil, 0, ErrPayloadSizeMismatch
+	}
+
+	total := headerSize + nameLen
+	if nameLen < 0 || total < headerSize || total > len(bytes) {
+		return nil, 0, ErrHeaderSizeOutOfBounds
+	}
+
+	crc, err := checksumOf(meta.ChecksumKind, bytes[5:total])
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc != meta.Crc {
+		return nil, 0, ErrBucketCrcInvalid
+	}
+
+	b = &Bucket{
+		Meta:      meta,
+		Id:        id,
+		Ds:        ds,
+		Timestamp: timestamp,
+		TTL:       ttl,
+		Name:      string(bytes[headerSize:total]),
+	}
+	return b, total, nil
 }
 
-// Encode : Meta | BucketId | Ds | BucketName
+// Encode : Crc(4 bytes) | ChecksumKind(1 byte) | Op | Size | LiveSize | Id | Ds | Timestamp | TTL | NameLen（后八项为变长整数） | BucketName
 func (b *Bucket) Encode() []byte {
-	entrySize := b.GetEntrySize()
-	buf := make([]byte, entrySize)
-	b.Meta.Size = uint32(b.GetPayloadSize())
-	binary.LittleEndian.PutUint16(buf[4:6], uint16(b.Meta.Op))
-	binary.LittleEndian.PutUint32(buf[6:10], b.Meta.Size)
-	binary.LittleEndian.PutUint64(buf[BucketMetaSize:BucketMetaSize+IdSize], uint64(b.Id))
-	binary.LittleEndian.PutUint16(buf[BucketMetaSize+IdSize:BucketMetaSize+IdSize+DsSize], uint16(b.Ds))
-	copy(buf[BucketMetaSize+IdSize+DsSize:], b.Name)
-	c32 := crc32.ChecksumIEEE(buf[4:])
+	nameLen := len(b.Name)
+	b.Meta.Size = uint32(nameLen)
+	b.Meta.ChecksumKind = DefaultChecksumKind()
+
+	header := make([]byte, MaxBucketHeaderSize)
+	header[4] = byte(b.Meta.ChecksumKind)
+	offset := 5
+	offset += binary.PutUvarint(header[offset:], uint64(b.Meta.Op))
+	offset += binary.PutUvarint(header[offset:], uint64(b.Meta.Size))
+	offset += binary.PutUvarint(header[offset:], b.Meta.LiveSize)
+	offset += binary.PutUvarint(header[offset:], uint64(b.Id))
+	offset += binary.PutUvarint(header[offset:], uint64(b.Ds))
+	offset += binary.PutUvarint(header[offset:], b.Timestamp)
+	offset += binary.PutUvarint(header[offset:], uint64(b.TTL))
+	offset += binary.PutUvarint(header[offset:], uint64(nameLen))
+	b.Meta.headerSize = offset
+
+	buf := make([]byte, offset+nameLen)
+	copy(buf, header[:offset])
+	copy(buf[offset:], b.Name)
+
+	c32, err := checksumOf(b.Meta.ChecksumKind, buf[5:])
+	if err != nil {
+		// defaultChecksum 只能通过 SetDefaultChecksum 设为受支持的算法，正常不会到这里。
+		panic(err)
+	}
 	b.Meta.Crc = c32
 	binary.LittleEndian.PutUint32(buf[0:4], c32)
 
 	return buf
 }
 
-// Decode : Meta | BucketId | Ds | BucketName
+// Decode : Crc(4 bytes) | ChecksumKind(1 byte) | Op | Size | LiveSize | Id | Ds | Timestamp | TTL | NameLen（后八项为变长整数） | BucketName
 func (b *Bucket) Decode(bytes []byte) error {
-	// parse the payload
-	id := binary.LittleEndian.Uint64(bytes[:IdSize])
-	ds := binary.LittleEndian.Uint16(bytes[IdSize : IdSize+DsSize])
-	name := bytes[IdSize+DsSize:]
-	b.Id = id
-	b.Name = string(name)
-	b.Ds = ds
+	decoded, _, err := DecodeBucket(bytes)
+	if err != nil {
+		return err
+	}
+	*b = *decoded
 	return nil
 }
 
 func (b *Bucket) GetEntrySize() int {
-	return int(BucketMetaSize) + b.GetPayloadSize()
+	payloadSize := b.GetPayloadSize()
+	return bucketHeaderSize(b.Meta.Op, uint32(payloadSize), b.Meta.LiveSize, b.Id, b.Ds, b.Timestamp, b.TTL, payloadSize) + payloadSize
 }
 
-func (b *Bucket) GetCRC(headerBuf []byte, dataBuf []byte) uint32 {
-	crc := crc32.ChecksumIEEE(headerBuf[4:])
-	crc = crc32.Update(crc, crc32.IEEETable, dataBuf)
-	return crc
+// GetCRC 按 b.Meta.ChecksumKind 指定的算法计算 headerBuf[5:]（跳过 Crc 与
+// ChecksumKind 本身）与 dataBuf 拼接后的校验和。
+func (b *Bucket) GetCRC(headerBuf []byte, dataBuf []byte) (uint32, error) {
+	return checksumOf(b.Meta.ChecksumKind, append(append([]byte{}, headerBuf[5:]...), dataBuf...))
 }
 
 func (b *Bucket) GetPayloadSize() int {
-	return IdSize + DsSize + len(b.Name)
+	return len(b.Name)
+}
+
+// bucketHeaderSize 计算 Op/Size/LiveSize/Id/Ds/Timestamp/TTL/NameLen 以变长
+// 整数编码后头部会占用的字节数（含固定 4 字节 Crc 与 1 字节 ChecksumKind），
+// 不写入任何数据，供 GetEntrySize 在不重新 Encode 的情况下估算记录总长度。
+func bucketHeaderSize(op BucketOperation, size uint32, liveSize uint64, id BucketId, ds Ds, timestamp uint64, ttl uint32, nameLen int) int {
+	return 5 +
+		uvarintSize(uint64(op)) +
+		uvarintSize(uint64(size)) +
+		uvarintSize(liveSize) +
+		uvarintSize(uint64(id)) +
+		uvarintSize(uint64(ds)) +
+		uvarintSize(timestamp) +
+		uvarintSize(uint64(ttl)) +
+		uvarintSize(uint64(nameLen))
+}
+
+// uvarintSize 返回 v 以 binary.PutUvarint 编码后占用的字节数。
+func uvarintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
 }
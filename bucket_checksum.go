@@ -0,0 +1,64 @@
+package nutsdb
+
+import (
+	"errors"
+	"hash/crc32"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ChecksumKind 标识存储桶头使用的校验和算法，随头部一起落盘，
+// 使得同一份数据在打开时总能用写入时的算法重新校验。
+type ChecksumKind uint8
+
+const (
+	// ChecksumCRC32IEEE 是历史上一直使用的 CRC32（IEEE 多项式），默认值。
+	ChecksumCRC32IEEE ChecksumKind = 0
+	// ChecksumCRC32Castagnoli 使用 CRC32C（Castagnoli 多项式），
+	// 在 amd64/arm64 上有 SSE4.2/ARMv8 硬件指令加速。
+	ChecksumCRC32Castagnoli ChecksumKind = 1
+	// ChecksumXXHash64 使用 xxhash64 并截断到 32 位，在现代 CPU 上比 CRC32 快得多。
+	ChecksumXXHash64 ChecksumKind = 2
+)
+
+// ErrUnsupportedChecksumKind 表示头部中记录的 ChecksumKind 不是本版本已知的算法，
+// 通常意味着数据文件由更新的版本写入。
+var ErrUnsupportedChecksumKind = errors.New("unsupported bucket checksum kind")
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// defaultChecksum 是新建存储桶头时使用的校验和算法，零值即 ChecksumCRC32IEEE，
+// 与历史数据保持兼容。它可能被 SetDefaultChecksum（经由 Options 在打开 DB 时）
+// 写入，也会被每一次 Bucket.Encode 并发读取，因此必须用原子操作访问，
+// 不能是裸的包级变量。
+var defaultChecksum atomic.Uint32
+
+// DefaultChecksumKind 返回当前生效的默认校验和算法。
+func DefaultChecksumKind() ChecksumKind {
+	return ChecksumKind(defaultChecksum.Load())
+}
+
+// SetDefaultChecksum 设置后续 Encode 新存储桶头时使用的校验和算法。
+func SetDefaultChecksum(kind ChecksumKind) error {
+	if _, err := checksumOf(kind, nil); err != nil {
+		return err
+	}
+	defaultChecksum.Store(uint32(kind))
+	return nil
+}
+
+// checksumOf 使用 kind 指定的算法计算 data 的校验和，并将结果截断/映射到 uint32，
+// 与落盘的 Crc 字段宽度保持一致。
+func checksumOf(kind ChecksumKind, data []byte) (uint32, error) {
+	switch kind {
+	case ChecksumCRC32IEEE:
+		return crc32.ChecksumIEEE(data), nil
+	case ChecksumCRC32Castagnoli:
+		return crc32.Checksum(data, castagnoliTable), nil
+	case ChecksumXXHash64:
+		return uint32(xxhash.Sum64(data)), nil
+	default:
+		return 0, ErrUnsupportedChecksumKind
+	}
+}
@@ -0,0 +1,64 @@
+package nutsdb
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBucketEncodeDecodeWithEachChecksumKind(t *testing.T) {
+	kinds := []ChecksumKind{ChecksumCRC32IEEE, ChecksumCRC32Castagnoli, ChecksumXXHash64}
+	for _, kind := range kinds {
+		if err := SetDefaultChecksum(kind); err != nil {
+			t.Fatalf("SetDefaultChecksum(%v) error = %v", kind, err)
+		}
+
+		b := &Bucket{Meta: &BucketMeta{Op: BucketInsertOperation}, Id: 1, Ds: 1, Name: "bucket"}
+		buf := b.Encode()
+
+		var decoded Bucket
+		if err := decoded.Decode(buf); err != nil {
+			t.Fatalf("Decode() with ChecksumKind %v error = %v", kind, err)
+		}
+		if decoded.Meta.ChecksumKind != kind {
+			t.Fatalf("decoded ChecksumKind = %v, want %v", decoded.Meta.ChecksumKind, kind)
+		}
+	}
+	if err := SetDefaultChecksum(ChecksumCRC32IEEE); err != nil {
+		t.Fatalf("SetDefaultChecksum() restore error = %v", err)
+	}
+}
+
+func TestBucketDecodeRejectsUnsupportedChecksumKind(t *testing.T) {
+	b := &Bucket{Meta: &BucketMeta{Op: BucketInsertOperation}, Id: 1, Ds: 1, Name: "bucket"}
+	buf := b.Encode()
+	buf[4] = 0xff // 覆盖成一个未知的 ChecksumKind
+
+	var decoded Bucket
+	if err := decoded.Decode(buf); err != ErrUnsupportedChecksumKind {
+		t.Fatalf("Decode() error = %v, want %v", err, ErrUnsupportedChecksumKind)
+	}
+}
+
+// TestSetDefaultChecksumConcurrentWithEncode 并发地调用 SetDefaultChecksum 与
+// Bucket.Encode，在竞态检测器（go test -race）下验证 defaultChecksum 的读写
+// 不会相互踩踏。
+func TestSetDefaultChecksumConcurrentWithEncode(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = SetDefaultChecksum(ChecksumCRC32Castagnoli)
+		}()
+		go func() {
+			defer wg.Done()
+			b := &Bucket{Meta: &BucketMeta{Op: BucketInsertOperation}, Id: 1, Ds: 1, Name: "bucket"}
+			b.Encode()
+		}()
+	}
+	wg.Wait()
+
+	if err := SetDefaultChecksum(ChecksumCRC32IEEE); err != nil {
+		t.Fatalf("SetDefaultChecksum() restore error = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package nutsdb
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBucketNotFound 表示给定 (Ds, Name) 下没有已加载的存储桶。
+var ErrBucketNotFound = errors.New("bucket not found")
+
+// BucketManager 在内存中维护每个 (Ds, Name) 当前的 *Bucket 状态，是
+// DB.BucketSize/DB.Buckets/DB.SetBucketTTL 等监控与管理类接口，以及条目写
+// 路径更新 LiveSize 时共同依赖的唯一数据源。
+//
+// mu 不仅保护 buckets 这个 map 本身，还串行化每一次“取出 -> 修改”的完整
+// 过程：ApplyEntry/SetTTL/expireDue/reclaimIfEmpty 都在持有 mu 的情况下
+// 直接原地修改已记录的 *Bucket，再释放锁，因此两次并发修改不会互相覆盖，
+// 也不会出现某次修改作用在一个已被 expireDue/reclaimIfEmpty 丢弃的存储桶
+// 上——查找与修改在同一次加锁里完成，丢弃之后的查找必然落空而不是拿着一个
+// 过期之前取到的指针继续写。GetBucket 只用于只读展示（BucketSize/
+// BucketInfo 等），返回的是一份深拷贝，调用方对它的修改不会影响
+// BucketManager 内部状态，也不需要额外加锁。
+type BucketManager struct {
+	mu      sync.RWMutex
+	buckets map[Ds]map[string]*Bucket
+}
+
+// NewBucketManager 创建一个空的 BucketManager。
+func NewBucketManager() *BucketManager {
+	return &BucketManager{buckets: make(map[Ds]map[string]*Bucket)}
+}
+
+// cloneBucket 返回 b 的一份深拷贝（包括 b.Meta 指向的 BucketMeta），供
+// GetBucket 对外返回只读快照时使用，避免调用方拿到 BucketManager 内部持有
+// 的指针。
+func cloneBucket(b *Bucket) *Bucket {
+	metaCopy := *b.Meta
+	bucketCopy := *b
+	bucketCopy.Meta = &metaCopy
+	return &bucketCopy
+}
+
+// GetBucket 返回 ds 下名为 name 的存储桶的一份快照，不存在时返回
+// ErrBucketNotFound。返回值是深拷贝：调用方可以自由读取其字段，但对它的
+// 修改不会写回 BucketManager；需要修改状态的调用方应使用 ApplyEntry/SetTTL。
+func (m *BucketManager) GetBucket(ds Ds, name string) (*Bucket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bucket, ok := m.buckets[ds][name]
+	if !ok {
+		return nil, ErrBucketNotFound
+	}
+	return cloneBucket(bucket), nil
+}
+
+// BucketNames 返回 ds 下当前已记录的所有存储桶名称，按字典序排列。
+func (m *BucketManager) BucketNames(ds Ds) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.buckets[ds]))
+	for name := range m.buckets[ds] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadedDataStructures 返回当前至少持有一个存储桶的所有 Ds，供后台任务
+// （例如 TTL 清扫器）遍历时使用，避免它需要知道全部 Ds 的静态列表。
+func (m *BucketManager) LoadedDataStructures() []Ds {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dss := make([]Ds, 0, len(m.buckets))
+	for ds, byName := range m.buckets {
+		if len(byName) == 0 {
+			continue
+		}
+		dss = append(dss, ds)
+	}
+	return dss
+}
+
+// PersistBucket 记录 bucket 的最新状态，覆盖同一个 (Ds, Name) 下之前记录的值。
+// 主要用于首次加载/恢复存储桶；日常的增量更新应使用 ApplyEntry/SetTTL，
+// 它们在同一次加锁内完成查找与修改，不会与其他更新互相踩踏。
+func (m *BucketManager) PersistBucket(bucket *Bucket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.buckets[bucket.Ds] == nil {
+		m.buckets[bucket.Ds] = make(map[string]*Bucket)
+	}
+	m.buckets[bucket.Ds][bucket.Name] = bucket
+	return nil
+}
+
+// ApplyEntry 把一次条目写入（插入/更新/删除）对 LiveSize 的影响落到 ds 下
+// 名为 name 的存储桶上。查找与修改在同一次加锁内完成，这是
+// BucketMeta.ApplyEntryDelta 在真实写路径上的唯一调用点，保证并发的
+// InsertEntry/UpdateEntry/DeleteEntry 不会互相覆盖彼此对 LiveSize 的增量。
+func (m *BucketManager) ApplyEntry(ds Ds, name string, op BucketOperation, delta int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[ds][name]
+	if !ok {
+		return ErrBucketNotFound
+	}
+	bucket.Meta.ApplyEntryDelta(op, delta)
+	return nil
+}
+
+// SetTTL 把 ds 下名为 name 的存储桶的 Timestamp/TTL 更新为给定值，并把其
+// Meta.Op 置为 BucketUpdateOperation；只修改内存中的状态，不写出任何记录。查找与修改在同一次加锁内完成，因此不会与后台
+// TTL 清扫器的 expireDue 交错出“先被判定过期丢弃、又被这次调用复活”的结果：
+// 两者共享同一把锁，谁先拿到锁谁的结果先生效，后拿到锁的一方看到的都是对方
+// 已经完成之后的状态。
+func (m *BucketManager) SetTTL(ds Ds, name string, timestamp uint64, ttl uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[ds][name]
+	if !ok {
+		return ErrBucketNotFound
+	}
+	bucket.Timestamp = timestamp
+	bucket.TTL = ttl
+	bucket.Meta.Op = BucketUpdateOperation
+	return nil
+}
+
+// expireDue 在持有 mu 的情况下重新判断 ds 下名为 name 的存储桶此刻是否仍然
+// 过期；如果是，则把其 Meta.Op 置为 BucketExpireOperation 并将其从内存中丢弃，
+// 返回 true。这里不写出任何过期记录，丢弃之后这一标记也不再能被读到。判断与丢弃是一次加锁内完成的原子操作：如果在清扫器上一次读到
+// 名字列表之后、真正丢弃之前，这个存储桶被 SetTTL/ApplyEntry 续期或刷新过，
+// 这里会看到更新后的状态从而判定为未过期，不会把它错误地丢弃。
+func (m *BucketManager) expireDue(ds Ds, name string, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[ds][name]
+	if !ok || !bucket.Expired(now) {
+		return false
+	}
+	bucket.Meta.Op = BucketExpireOperation
+	delete(m.buckets[ds], name)
+	return true
+}
+
+// reclaimIfEmpty 在持有 mu 的情况下重新确认 ds 下名为 name 的存储桶此刻的
+// LiveSize 是否仍为 0；如果是，则将其丢弃并返回 true。确认与丢弃是一次加锁
+// 内完成的原子操作，不会丢弃一个在调用方读到 LiveSize==0、到真正丢弃之间又
+// 被 ApplyEntry 写入了新数据的存储桶。
+func (m *BucketManager) reclaimIfEmpty(ds Ds, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[ds][name]
+	if !ok {
+		return false, ErrBucketNotFound
+	}
+	if bucket.Meta.LiveSize != 0 {
+		return false, nil
+	}
+	delete(m.buckets[ds], name)
+	return true, nil
+}
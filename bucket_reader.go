@@ -0,0 +1,146 @@
+package nutsdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BucketCrcError 包装 ErrBucketCrcInvalid，并携带出问题的记录在文件中的
+// 起始偏移，便于调用方定位、跳过损坏记录后继续读取（skip-and-continue）。
+type BucketCrcError struct {
+	Offset int64
+}
+
+func (e *BucketCrcError) Error() string {
+	return fmt.Sprintf("bucket crc invalid at offset %d: %v", e.Offset, ErrBucketCrcInvalid)
+}
+
+func (e *BucketCrcError) Unwrap() error {
+	return ErrBucketCrcInvalid
+}
+
+// BucketFileReader 以流式方式遍历存储桶元数据文件，每次只读取一条记录的
+// 头部和负载，不要求调用方预先把整份文件读入内存，适用于体积较大、无法
+// 整体装入内存的部署场景。
+type BucketFileReader struct {
+	r      io.ReaderAt
+	size   int64
+	offset int64
+}
+
+// NewBucketFileReader 创建一个从 offset 处开始读取的 BucketFileReader。
+// offset 传 0 即从文件开头读取；传一个之前记录下来的偏移量即可从中间恢复。
+func NewBucketFileReader(r io.ReaderAt, size int64, offset int64) *BucketFileReader {
+	return &BucketFileReader{r: r, size: size, offset: offset}
+}
+
+// Offset 返回下一条待读取记录的起始偏移。
+func (it *BucketFileReader) Offset() int64 {
+	return it.offset
+}
+
+// Next 读取并返回下一条存储桶记录。文件读完后返回 io.EOF。
+//
+// 如果某条记录的头部本身已经无法解析（数据被截断或损坏到无法确定记录长度），
+// Next 返回该错误且不再推进 offset，调用方应当停止遍历。如果头部能正常解析
+// 但负载的 Crc 校验失败，Next 返回 *BucketCrcError 并仍然把 offset 推进到
+// 下一条记录，调用方可以忽略该错误继续调用 Next 完成跳过并继续读取。
+func (it *BucketFileReader) Next() (*Bucket, error) {
+	if it.size > 0 && it.offset >= it.size {
+		return nil, io.EOF
+	}
+
+	headerBuf := make([]byte, MaxBucketHeaderSize)
+	n, err := it.r.ReadAt(headerBuf, it.offset)
+	if n == 0 && err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	headerBuf = headerBuf[:n]
+
+	meta, id, ds, timestamp, ttl, nameLen, headerSize, err := DecodeBucketHeader(headerBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	total := headerSize + nameLen
+	if it.size > 0 && int64(total) > it.size-it.offset {
+		// 头部本身能解析，但它声明的记录长度超出了文件剩余的字节数：文件被
+		// 截断或这条记录被破坏了。不要据此分配/读取，当作头部不可信处理。
+		return nil, ErrHeaderSizeOutOfBounds
+	}
+
+	recordBuf := headerBuf
+	if total > len(recordBuf) {
+		recordBuf = make([]byte, total)
+		if _, err := it.r.ReadAt(recordBuf, it.offset); err != nil {
+			return nil, err
+		}
+	}
+
+	crc, err := checksumOf(meta.ChecksumKind, recordBuf[5:total])
+	if err != nil {
+		return nil, err
+	}
+	if crc != meta.Crc {
+		badOffset := it.offset
+		it.offset += int64(total)
+		return nil, &BucketCrcError{Offset: badOffset}
+	}
+
+	b := &Bucket{
+		Meta:      meta,
+		Id:        id,
+		Ds:        ds,
+		Timestamp: timestamp,
+		TTL:       ttl,
+		Name:      string(recordBuf[headerSize:total]),
+	}
+	it.offset += int64(total)
+	return b, nil
+}
+
+// ForEachBucket 打开 path 指向的存储桶元数据文件，依次调用 fn 处理其中每一
+// 条记录；遇到 Crc 校验失败的记录会跳过并继续，其余错误会中止遍历并返回。
+func ForEachBucket(path string, fn func(*Bucket) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return ForEachBucketFrom(f, info.Size(), 0, fn)
+}
+
+// ForEachBucketFrom 与 ForEachBucket 相同，但允许调用方传入已打开的
+// io.ReaderAt、文件大小以及起始偏移，用于从上一次记录的位置恢复遍历。
+func ForEachBucketFrom(r io.ReaderAt, size int64, offset int64, fn func(*Bucket) error) error {
+	it := NewBucketFileReader(r, size, offset)
+	for {
+		b, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var crcErr *BucketCrcError
+		if errors.As(err, &crcErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package nutsdb
+
+// BucketSize 返回 ds 下名为 name 的存储桶当前的有效负载总大小（即 LiveSize，
+// 已排除被墓碑标记/删除的条目），供运维做监控或配额判断使用，无需再扫描
+// 该存储桶下的全部条目文件。
+func (db *DB) BucketSize(ds Ds, name string) (uint64, error) {
+	bucket, err := db.bucketManager.GetBucket(ds, name)
+	if err != nil {
+		return 0, err
+	}
+	return bucket.Meta.LiveSize, nil
+}
+
+// Buckets 返回 ds 下当前存在的所有存储桶名称。
+func (db *DB) Buckets(ds Ds) ([]string, error) {
+	return db.bucketManager.BucketNames(ds)
+}
+
+// reclaimEmptyBucketSizes 遍历 ds 下已记录的存储桶，把 LiveSize 为 0 的存储桶
+// 整条记录从 BucketManager 中丢弃（LiveSize 保存在存储桶记录里，没有单独的
+// 计数条目；丢弃后该存储桶也不再出现在 Buckets/BucketSize 中）。目前在 DB.Open
+// 启动阶段对彼时已加载的存储桶执行一次；这个仓库还没有 Merge 流程，引入后
+// 也应该在其结束时同样调用一次。确认 LiveSize 仍为 0 与丢弃记录由
+// BucketManager.reclaimIfEmpty 在同一次加锁内完成，不会丢弃一个在这里读到
+// 名字列表之后又被写入了新数据的存储桶。
+func (db *DB) reclaimEmptyBucketSizes(ds Ds) error {
+	names, err := db.bucketManager.BucketNames(ds)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := db.bucketManager.reclaimIfEmpty(ds, name); err != nil && err != ErrBucketNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
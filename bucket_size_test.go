@@ -0,0 +1,145 @@
+package nutsdb
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDBBucketSizeTracksWrites(t *testing.T) {
+	db := NewDB()
+	bucket := &Bucket{Meta: &BucketMeta{Op: BucketInsertOperation}, Id: 1, Ds: 1, Name: "b"}
+	if err := db.bucketManager.PersistBucket(bucket); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	if err := db.InsertEntry(1, "b", 100); err != nil {
+		t.Fatalf("InsertEntry() error = %v", err)
+	}
+	if err := db.InsertEntry(1, "b", 50); err != nil {
+		t.Fatalf("InsertEntry() error = %v", err)
+	}
+
+	size, err := db.BucketSize(1, "b")
+	if err != nil {
+		t.Fatalf("BucketSize() error = %v", err)
+	}
+	if size != 150 {
+		t.Fatalf("BucketSize() = %d, want 150", size)
+	}
+
+	if err := db.UpdateEntry(1, "b", 50, 20); err != nil {
+		t.Fatalf("UpdateEntry() error = %v", err)
+	}
+	if size, _ = db.BucketSize(1, "b"); size != 120 {
+		t.Fatalf("BucketSize() after update = %d, want 120", size)
+	}
+
+	if err := db.DeleteEntry(1, "b", 100); err != nil {
+		t.Fatalf("DeleteEntry() error = %v", err)
+	}
+	if size, _ = db.BucketSize(1, "b"); size != 20 {
+		t.Fatalf("BucketSize() after delete = %d, want 20", size)
+	}
+}
+
+func TestDBBuckets(t *testing.T) {
+	db := NewDB()
+	for _, name := range []string{"b", "a", "c"} {
+		if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: name}); err != nil {
+			t.Fatalf("PersistBucket(%q) error = %v", name, err)
+		}
+	}
+
+	names, err := db.Buckets(1)
+	if err != nil {
+		t.Fatalf("Buckets() error = %v", err)
+	}
+	want := []string{"a", "b", "c"}
+	if len(names) != len(want) {
+		t.Fatalf("Buckets() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Buckets() = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestDBInsertEntryConcurrentDoesNotLoseUpdates(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "b"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	const writers = 100
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for i := 0; i < writers; i++ {
+		go func() {
+			defer wg.Done()
+			if err := db.InsertEntry(1, "b", 1); err != nil {
+				t.Errorf("InsertEntry() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	size, err := db.BucketSize(1, "b")
+	if err != nil {
+		t.Fatalf("BucketSize() error = %v", err)
+	}
+	if size != writers {
+		t.Fatalf("BucketSize() = %d, want %d (concurrent InsertEntry calls must not lose updates)", size, writers)
+	}
+}
+
+func TestDBReclaimEmptyBucketSizes(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "empty"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{LiveSize: 10}, Ds: 1, Name: "live"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	if err := db.reclaimEmptyBucketSizes(1); err != nil {
+		t.Fatalf("reclaimEmptyBucketSizes() error = %v", err)
+	}
+
+	if _, err := db.bucketManager.GetBucket(1, "empty"); err != ErrBucketNotFound {
+		t.Fatalf("GetBucket(empty) error = %v, want %v", err, ErrBucketNotFound)
+	}
+	if _, err := db.bucketManager.GetBucket(1, "live"); err != nil {
+		t.Fatalf("GetBucket(live) error = %v, want nil", err)
+	}
+}
+
+func TestDBReclaimEmptyBucketSizesDoesNotDropConcurrentWrite(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "b"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	// 模拟在 reclaimEmptyBucketSizes 读到名字列表之后、调用 reclaimIfEmpty 之前
+	// 到达的一次写入，reclaimIfEmpty 必须在加锁后重新确认 LiveSize，不能把这次
+	// 写入刚插入的数据当作孤儿丢弃。
+	if err := db.InsertEntry(1, "b", 10); err != nil {
+		t.Fatalf("InsertEntry() error = %v", err)
+	}
+
+	ok, err := db.bucketManager.reclaimIfEmpty(1, "b")
+	if err != nil {
+		t.Fatalf("reclaimIfEmpty() error = %v", err)
+	}
+	if ok {
+		t.Fatal("reclaimIfEmpty() = true, want false for a bucket with a nonzero LiveSize")
+	}
+
+	size, err := db.BucketSize(1, "b")
+	if err != nil {
+		t.Fatalf("BucketSize() error = %v", err)
+	}
+	if size != 10 {
+		t.Fatalf("BucketSize() = %d, want 10 (reclaimIfEmpty must not have dropped it)", size)
+	}
+}
@@ -0,0 +1,69 @@
+package nutsdb
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestBucketEncodeDecodeRoundTrip(t *testing.T) {
+	b := &Bucket{
+		Meta:      &BucketMeta{Op: BucketInsertOperation},
+		Id:        42,
+		Ds:        1,
+		Timestamp: 1700000000,
+		TTL:       3600,
+		Name:      "bucket_1",
+	}
+
+	buf := b.Encode()
+
+	var decoded Bucket
+	if err := decoded.Decode(buf); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if decoded.Id != b.Id || decoded.Ds != b.Ds || decoded.Name != b.Name ||
+		decoded.Timestamp != b.Timestamp || decoded.TTL != b.TTL {
+		t.Fatalf("decoded bucket = %+v, want fields matching %+v", decoded, b)
+	}
+	if decoded.Meta.Op != b.Meta.Op {
+		t.Fatalf("decoded Op = %v, want %v", decoded.Meta.Op, b.Meta.Op)
+	}
+}
+
+// TestBucketDecodeRejectsHugeNameLen 构造一个头部中 NameLen 字段被破坏成
+// 接近 uint64 上限的记录（最高位为 1），在未加边界检查时会在 64 位平台上
+// 被裸转换成负的 int，使 total := headerSize + nameLen 变成负数，从而绕过
+// `total > len(bytes)` 检查并引发越界 panic。
+func TestBucketDecodeRejectsHugeNameLen(t *testing.T) {
+	buf := make([]byte, MaxBucketHeaderSize)
+	offset := 5
+	offset += binary.PutUvarint(buf[offset:], uint64(BucketInsertOperation)) // Op
+	offset += binary.PutUvarint(buf[offset:], 0)                             // Size
+	offset += binary.PutUvarint(buf[offset:], 0)                             // LiveSize
+	offset += binary.PutUvarint(buf[offset:], 1)                             // Id
+	offset += binary.PutUvarint(buf[offset:], 1)                             // Ds
+	offset += binary.PutUvarint(buf[offset:], 0)                             // Timestamp
+	offset += binary.PutUvarint(buf[offset:], 0)                             // TTL
+	offset += binary.PutUvarint(buf[offset:], 1<<63)                         // NameLen, corrupted
+
+	var decoded Bucket
+	if err := decoded.Decode(buf[:offset]); err != ErrHeaderSizeOutOfBounds {
+		t.Fatalf("Decode() with a corrupted huge NameLen error = %v, want %v (and must not panic)", err, ErrHeaderSizeOutOfBounds)
+	}
+}
+
+func TestBucketDecodeRejectsTruncatedHeader(t *testing.T) {
+	b := &Bucket{
+		Meta: &BucketMeta{Op: BucketInsertOperation},
+		Id:   1,
+		Ds:   1,
+		Name: "bucket",
+	}
+	buf := b.Encode()
+
+	var decoded Bucket
+	if err := decoded.Decode(buf[:MinBucketHeaderSize-1]); err != ErrHeaderSizeOutOfBounds {
+		t.Fatalf("Decode() on truncated header error = %v, want %v", err, ErrHeaderSizeOutOfBounds)
+	}
+}
@@ -0,0 +1,130 @@
+package nutsdb
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// defaultBucketTTLSweepInterval 是后台过期清扫器在未显式配置时使用的扫描周期。
+const defaultBucketTTLSweepInterval = time.Minute
+
+var (
+	// ErrNegativeBucketTTL 表示调用方传入了一个负的 TTL。TTL 在磁盘上以
+	// uint32 秒数编码，负的 time.Duration 转换成 uint32 会直接环绕成一个
+	// 巨大的正数，相当于把存储桶设置成几乎永不过期，因此必须在转换前拒绝。
+	ErrNegativeBucketTTL = errors.New("bucket ttl must not be negative")
+	// ErrBucketTTLTooLarge 表示 ttl 换算成秒后超出了 uint32 能表示的范围。
+	ErrBucketTTLTooLarge = errors.New("bucket ttl exceeds representable range")
+)
+
+// BucketInfo 是 DB.BucketInfo 返回的存储桶概览，用于在不直接触碰底层数据
+// 结构的情况下查看一个存储桶的元信息，包括它还能存活多久。
+type BucketInfo struct {
+	Id       BucketId
+	Ds       Ds
+	Name     string
+	LiveSize uint64
+	// TTL 为 0 表示此存储桶没有设置过期时间。
+	TTL time.Duration
+	// RemainingTTL 是距离该存储桶过期还剩下的时间；没有设置 TTL 时为 0。
+	RemainingTTL time.Duration
+}
+
+// SetBucketTTL 为 ds 下名为 name 的存储桶设置存活时长，ttl 为 0 表示取消过期。
+// ttl 不能为负（会返回 ErrNegativeBucketTTL），换算成的秒数也不能超出 uint32
+// 能表示的范围（会返回 ErrBucketTTLTooLarge）——这两种情况本该是调用方的
+// 笔误，不应该被静默地环绕成一个几乎永不过期的巨大 TTL。校验通过后，该调用
+// 把此存储桶的 Meta.Op 置为 BucketUpdateOperation（只改内存状态，不写出
+// 记录），Timestamp 被刷新为当前时间，TTL 从此刻开始重新计时。
+func (db *DB) SetBucketTTL(ds Ds, name string, ttl time.Duration) error {
+	if ttl < 0 {
+		return ErrNegativeBucketTTL
+	}
+	seconds := ttl / time.Second
+	if seconds > math.MaxUint32 {
+		return ErrBucketTTLTooLarge
+	}
+
+	return db.bucketManager.SetTTL(ds, name, uint64(time.Now().Unix()), uint32(seconds))
+}
+
+// BucketInfo 返回 ds 下名为 name 的存储桶的概览信息，RemainingTTL 基于当前
+// 时间与 Timestamp+TTL 计算得出。
+func (db *DB) BucketInfo(ds Ds, name string) (*BucketInfo, error) {
+	bucket, err := db.bucketManager.GetBucket(ds, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &BucketInfo{
+		Id:       bucket.Id,
+		Ds:       bucket.Ds,
+		Name:     bucket.Name,
+		LiveSize: bucket.Meta.LiveSize,
+		TTL:      time.Duration(bucket.TTL) * time.Second,
+	}
+	if bucket.TTL != 0 {
+		deadline := time.Unix(int64(bucket.Timestamp), 0).Add(info.TTL)
+		if remaining := deadline.Sub(time.Now()); remaining > 0 {
+			info.RemainingTTL = remaining
+		}
+	}
+
+	return info, nil
+}
+
+// bucketTTLSweeper 周期性扫描当前已加载的存储桶，把过期存储桶的 Meta.Op 置为
+// BucketExpireOperation 并将其从内存中丢弃。它不写出任何过期记录。
+type bucketTTLSweeper struct {
+	db       *DB
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// startBucketTTLSweeper 启动后台过期清扫器，返回的 sweeper 可通过 stop 关闭。
+func (db *DB) startBucketTTLSweeper(interval time.Duration) *bucketTTLSweeper {
+	if interval <= 0 {
+		interval = defaultBucketTTLSweepInterval
+	}
+
+	sweeper := &bucketTTLSweeper{db: db, interval: interval, stop: make(chan struct{})}
+	go sweeper.run()
+	return sweeper
+}
+
+func (s *bucketTTLSweeper) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweepOnce()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sweepOnce 只用存储桶名字做遍历，真正的“是否仍然过期”判断和丢弃都委托给
+// BucketManager.expireDue 在同一次加锁内完成，避免这里取到的 *Bucket 快照
+// 在判断之后、丢弃之前被别的 goroutine 续期，从而把一个已经不该过期的存储
+// 桶错误地丢弃掉。
+func (s *bucketTTLSweeper) sweepOnce() {
+	now := time.Now()
+	for _, ds := range s.db.bucketManager.LoadedDataStructures() {
+		names, err := s.db.bucketManager.BucketNames(ds)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			s.db.bucketManager.expireDue(ds, name, now)
+		}
+	}
+}
+
+func (s *bucketTTLSweeper) Close() {
+	close(s.stop)
+}
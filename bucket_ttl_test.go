@@ -0,0 +1,145 @@
+package nutsdb
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDBSetBucketTTLAndBucketInfo(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "b"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	if err := db.SetBucketTTL(1, "b", time.Hour); err != nil {
+		t.Fatalf("SetBucketTTL() error = %v", err)
+	}
+
+	info, err := db.BucketInfo(1, "b")
+	if err != nil {
+		t.Fatalf("BucketInfo() error = %v", err)
+	}
+	if info.TTL != time.Hour {
+		t.Fatalf("BucketInfo().TTL = %v, want %v", info.TTL, time.Hour)
+	}
+	if info.RemainingTTL <= 0 || info.RemainingTTL > time.Hour {
+		t.Fatalf("BucketInfo().RemainingTTL = %v, want (0, 1h]", info.RemainingTTL)
+	}
+}
+
+func TestBucketTTLSweeperExpiresBuckets(t *testing.T) {
+	db := NewDB()
+	expired := &Bucket{
+		Meta:      &BucketMeta{},
+		Ds:        1,
+		Name:      "expired",
+		Timestamp: uint64(time.Now().Add(-2 * time.Second).Unix()),
+		TTL:       1,
+	}
+	alive := &Bucket{
+		Meta:      &BucketMeta{},
+		Ds:        1,
+		Name:      "alive",
+		Timestamp: uint64(time.Now().Unix()),
+		TTL:       3600,
+	}
+	if err := db.bucketManager.PersistBucket(expired); err != nil {
+		t.Fatalf("PersistBucket(expired) error = %v", err)
+	}
+	if err := db.bucketManager.PersistBucket(alive); err != nil {
+		t.Fatalf("PersistBucket(alive) error = %v", err)
+	}
+
+	sweeper := db.startBucketTTLSweeper(10 * time.Millisecond)
+	defer sweeper.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := db.bucketManager.GetBucket(1, "expired"); err == ErrBucketNotFound {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := db.bucketManager.GetBucket(1, "expired"); err != ErrBucketNotFound {
+		t.Fatalf("expired bucket should have been swept, GetBucket() error = %v", err)
+	}
+	if _, err := db.bucketManager.GetBucket(1, "alive"); err != nil {
+		t.Fatalf("alive bucket should not have been swept, GetBucket() error = %v", err)
+	}
+}
+
+func TestDBSetBucketTTLRejectsNegativeDuration(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "b"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	if err := db.SetBucketTTL(1, "b", -5*time.Second); err != ErrNegativeBucketTTL {
+		t.Fatalf("SetBucketTTL(negative) error = %v, want %v", err, ErrNegativeBucketTTL)
+	}
+
+	info, err := db.BucketInfo(1, "b")
+	if err != nil {
+		t.Fatalf("BucketInfo() error = %v", err)
+	}
+	if info.TTL != 0 {
+		t.Fatalf("BucketInfo().TTL = %v, want 0 (rejected SetBucketTTL must not have taken effect)", info.TTL)
+	}
+}
+
+func TestDBSetBucketTTLRejectsOverflowingDuration(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{Meta: &BucketMeta{}, Ds: 1, Name: "b"}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	tooLarge := time.Duration(math.MaxUint32+1) * time.Second
+	if err := db.SetBucketTTL(1, "b", tooLarge); err != ErrBucketTTLTooLarge {
+		t.Fatalf("SetBucketTTL(overflowing) error = %v, want %v", err, ErrBucketTTLTooLarge)
+	}
+}
+
+func TestBucketTTLSweeperDoesNotResurrectAfterExpiry(t *testing.T) {
+	db := NewDB()
+	if err := db.bucketManager.PersistBucket(&Bucket{
+		Meta:      &BucketMeta{},
+		Ds:        1,
+		Name:      "b",
+		Timestamp: uint64(time.Now().Add(-2 * time.Second).Unix()),
+		TTL:       1,
+	}); err != nil {
+		t.Fatalf("PersistBucket() error = %v", err)
+	}
+
+	// 直接调用 expireDue 模拟清扫器已经判定过期并丢弃，随后一个携带旧状态的
+	// 并发写入方试图把它续期；SetTTL 必须因为找不到这个已被丢弃的存储桶而
+	// 失败，而不是把它复活回来。
+	if !db.bucketManager.expireDue(1, "b", time.Now()) {
+		t.Fatal("expireDue() = false, want true for an already-expired bucket")
+	}
+
+	if err := db.SetBucketTTL(1, "b", time.Hour); err != ErrBucketNotFound {
+		t.Fatalf("SetBucketTTL() on an expired-and-dropped bucket error = %v, want %v (resurrection)", err, ErrBucketNotFound)
+	}
+	if _, err := db.bucketManager.GetBucket(1, "b"); err != ErrBucketNotFound {
+		t.Fatalf("GetBucket() after expiry error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+func TestOpenStartsAndCloseStopsSweeper(t *testing.T) {
+	db, err := Open(DefaultOptions())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if db.ttlSweeper == nil {
+		t.Fatal("Open() did not start a TTL sweeper")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if db.ttlSweeper != nil {
+		t.Fatal("Close() did not clear the TTL sweeper")
+	}
+}
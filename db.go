@@ -0,0 +1,71 @@
+package nutsdb
+
+// DB 是此存储桶子系统依赖的宿主：它持有当前进程中已加载的存储桶状态，
+// BucketSize/Buckets/SetBucketTTL 等管理类接口都通过它访问。
+type DB struct {
+	bucketManager *BucketManager
+	ttlSweeper    *bucketTTLSweeper
+}
+
+// NewDB 创建一个新的 DB，其 BucketManager 为空，尚未加载任何存储桶，
+// 校验和算法等选项保持当前的默认值，也不启动 TTL 清扫器。多数测试场景
+// 直接用它即可；需要过期语义生效的调用方应改用 Open。
+func NewDB() *DB {
+	return &DB{bucketManager: NewBucketManager()}
+}
+
+// NewDBWithOptions 创建一个新的 DB 并应用 opts，例如把
+// opts.BucketChecksumKind 设为后续 Bucket.Encode 使用的默认校验和算法。
+func NewDBWithOptions(opts Options) (*DB, error) {
+	if err := SetDefaultChecksum(opts.BucketChecksumKind); err != nil {
+		return nil, err
+	}
+	return NewDB(), nil
+}
+
+// Open 创建一个新的 DB 并应用 opts，对彼时已加载的存储桶回收一遍 LiveSize
+// 为 0 的孤儿计数条目，再启动后台的存储桶 TTL 清扫器，使 SetBucketTTL 设置
+// 的过期时间能够真正生效。调用方应在不再使用该 DB 时调用 Close 停止清扫器。
+func Open(opts Options) (*DB, error) {
+	db, err := NewDBWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ds := range db.bucketManager.LoadedDataStructures() {
+		if err := db.reclaimEmptyBucketSizes(ds); err != nil {
+			return nil, err
+		}
+	}
+
+	db.ttlSweeper = db.startBucketTTLSweeper(defaultBucketTTLSweepInterval)
+	return db, nil
+}
+
+// Close 停止由 Open 启动的后台 TTL 清扫器。对 NewDB/NewDBWithOptions 创建、
+// 从未启动过清扫器的 DB 调用 Close 是安全的空操作。
+func (db *DB) Close() error {
+	if db.ttlSweeper != nil {
+		db.ttlSweeper.Close()
+		db.ttlSweeper = nil
+	}
+	return nil
+}
+
+// InsertEntry 记录向 ds 下名为 name 的存储桶插入一条大小为 size 的条目，
+// 并把 size 计入该存储桶的 LiveSize。
+func (db *DB) InsertEntry(ds Ds, name string, size int64) error {
+	return db.bucketManager.ApplyEntry(ds, name, BucketInsertOperation, size)
+}
+
+// UpdateEntry 记录 ds 下名为 name 的存储桶中一条条目被更新：oldSize 是它更新
+// 前的负载大小，newSize 是更新后的负载大小，两者之差计入该存储桶的 LiveSize。
+func (db *DB) UpdateEntry(ds Ds, name string, oldSize, newSize int64) error {
+	return db.bucketManager.ApplyEntry(ds, name, BucketUpdateOperation, newSize-oldSize)
+}
+
+// DeleteEntry 记录向 ds 下名为 name 的存储桶删除一条大小为 size 的条目，
+// 并从该存储桶的 LiveSize 中扣除 size。
+func (db *DB) DeleteEntry(ds Ds, name string, size int64) error {
+	return db.bucketManager.ApplyEntry(ds, name, BucketDeleteOperation, -size)
+}
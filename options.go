@@ -0,0 +1,14 @@
+package nutsdb
+
+// Options 收纳打开 DB 时可配置的选项。目前只暴露存储桶头校验和算法，
+// 后续新增的可配置项应当继续加在这里，而不是散落成包级变量。
+type Options struct {
+	// BucketChecksumKind 指定新建存储桶头使用的校验和算法。零值
+	// （ChecksumCRC32IEEE）与历史数据保持兼容。
+	BucketChecksumKind ChecksumKind
+}
+
+// DefaultOptions 返回与历史行为一致的默认 Options。
+func DefaultOptions() Options {
+	return Options{BucketChecksumKind: ChecksumCRC32IEEE}
+}